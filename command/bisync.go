@@ -0,0 +1,592 @@
+package command
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/peak/s5cmd/v2/storage/url"
+	"github.com/urfave/cli/v2"
+)
+
+// BisyncEntry is one line of a bisync state file: the last known identity
+// of a single key, as observed on one side of a bidirectional sync pair
+// during the previous successful run.
+type BisyncEntry struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	Hash    string    `json:"hash"`
+}
+
+// readBisyncState reads a newline-delimited JSON state file written by a
+// previous successful bisync run. A missing file is treated as an empty
+// state, as happens on the very first run.
+func readBisyncState(path string) (map[string]BisyncEntry, error) {
+	state := map[string]BisyncEntry{}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var entry BisyncEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("corrupt bisync state file %q: %w", path, err)
+		}
+		state[entry.Key] = entry
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// writeBisyncState writes state to path as newline-delimited JSON, sorted
+// by key so the file stays diffable across runs. The write is atomic: it's
+// built up in a temp file alongside path and renamed into place, so a run
+// interrupted mid-write can never leave a half-written state file behind.
+func writeBisyncState(path string, state map[string]BisyncEntry) error {
+	keys := make([]string, 0, len(state))
+	for k := range state {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	w := bufio.NewWriter(tmp)
+	for _, k := range keys {
+		data, err := json.Marshal(state[k])
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// BisyncAction classifies how a single key should be treated in a bisync
+// run, relative to the state file recorded by the previous run.
+type BisyncAction int
+
+const (
+	BisyncUnchanged BisyncAction = iota
+	BisyncModifiedOnA
+	BisyncModifiedOnB
+	BisyncNewOnA
+	BisyncNewOnB
+	BisyncDeletedOnA
+	BisyncDeletedOnB
+	BisyncConflict
+)
+
+// classifyBisyncKey compares one key's entries on side A, side B, and in
+// the previous state (any of which may be absent, indicated by the
+// corresponding ok flag), and classifies the key accordingly.
+func classifyBisyncKey(prev BisyncEntry, prevOK bool, a BisyncEntry, aOK bool, b BisyncEntry, bOK bool) BisyncAction {
+	changedSincePrev := func(e BisyncEntry) bool {
+		return !prevOK || e.Hash != prev.Hash || e.Size != prev.Size
+	}
+
+	switch {
+	case aOK && bOK:
+		switch aChanged, bChanged := changedSincePrev(a), changedSincePrev(b); {
+		case !prevOK:
+			// Neither side has been through a sync before: if the content
+			// already agrees there's nothing to do, otherwise it's a
+			// genuine conflict for --conflict to resolve.
+			if a.Hash == b.Hash && a.Size == b.Size {
+				return BisyncUnchanged
+			}
+			return BisyncConflict
+		case aChanged && bChanged:
+			return BisyncConflict
+		case aChanged:
+			return BisyncModifiedOnA
+		case bChanged:
+			return BisyncModifiedOnB
+		default:
+			return BisyncUnchanged
+		}
+	case aOK && !bOK:
+		if prevOK {
+			return BisyncDeletedOnB
+		}
+		return BisyncNewOnA
+	case !aOK && bOK:
+		if prevOK {
+			return BisyncDeletedOnA
+		}
+		return BisyncNewOnB
+	default:
+		// Gone from both sides and the state: nothing left to reconcile.
+		return BisyncUnchanged
+	}
+}
+
+// ConflictPolicy selects how bisync resolves a key that was modified on
+// both sides since the last successful run.
+type ConflictPolicy string
+
+// Supported --conflict values.
+const (
+	ConflictNewer      ConflictPolicy = "newer"
+	ConflictLarger     ConflictPolicy = "larger"
+	ConflictPath1      ConflictPolicy = "path1"
+	ConflictPath2      ConflictPolicy = "path2"
+	ConflictRenameBoth ConflictPolicy = "rename-both"
+)
+
+// ConflictResolution is the outcome of applying a ConflictPolicy to a
+// single conflicting key.
+type ConflictResolution struct {
+	// CopyAToB and CopyBToA select which side, if any, wins outright and
+	// overwrites the other. At most one is ever set.
+	CopyAToB bool
+	CopyBToA bool
+
+	// RenameBoth means neither side should win: the key is instead split
+	// into two new, non-conflicting keys (e.g. "file.txt.path1" and
+	// "file.txt.path2") so no data is lost.
+	RenameBoth bool
+}
+
+// resolveConflict applies policy to a key that was modified on both sides.
+func resolveConflict(policy ConflictPolicy, a, b BisyncEntry) (ConflictResolution, error) {
+	switch policy {
+	case ConflictNewer:
+		if a.ModTime.After(b.ModTime) {
+			return ConflictResolution{CopyAToB: true}, nil
+		}
+		return ConflictResolution{CopyBToA: true}, nil
+	case ConflictLarger:
+		if a.Size >= b.Size {
+			return ConflictResolution{CopyAToB: true}, nil
+		}
+		return ConflictResolution{CopyBToA: true}, nil
+	case ConflictPath1:
+		return ConflictResolution{CopyAToB: true}, nil
+	case ConflictPath2:
+		return ConflictResolution{CopyBToA: true}, nil
+	case ConflictRenameBoth:
+		return ConflictResolution{RenameBoth: true}, nil
+	default:
+		return ConflictResolution{}, fmt.Errorf("bisync: unknown conflict policy %q", policy)
+	}
+}
+
+// BisyncPlan is the set of operations a bisync run must perform to bring
+// side A and side B back into agreement.
+type BisyncPlan struct {
+	CopyToB    []string
+	CopyToA    []string
+	DeleteOnA  []string
+	DeleteOnB  []string
+	RenameBoth []string
+}
+
+// TotalDeletes returns how many delete operations the plan contains, the
+// quantity --max-delete guards.
+func (p BisyncPlan) TotalDeletes() int {
+	return len(p.DeleteOnA) + len(p.DeleteOnB)
+}
+
+// planBisync classifies every key across prev/a/b and builds the
+// resulting BisyncPlan, resolving conflicts with policy.
+func planBisync(prev, a, b map[string]BisyncEntry, policy ConflictPolicy) (BisyncPlan, error) {
+	keys := make(map[string]struct{}, len(prev)+len(a)+len(b))
+	for k := range prev {
+		keys[k] = struct{}{}
+	}
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+
+	var plan BisyncPlan
+	for key := range keys {
+		prevEntry, prevOK := prev[key]
+		aEntry, aOK := a[key]
+		bEntry, bOK := b[key]
+
+		switch classifyBisyncKey(prevEntry, prevOK, aEntry, aOK, bEntry, bOK) {
+		case BisyncNewOnA, BisyncModifiedOnA:
+			plan.CopyToB = append(plan.CopyToB, key)
+		case BisyncNewOnB, BisyncModifiedOnB:
+			plan.CopyToA = append(plan.CopyToA, key)
+		case BisyncDeletedOnA:
+			plan.DeleteOnB = append(plan.DeleteOnB, key)
+		case BisyncDeletedOnB:
+			plan.DeleteOnA = append(plan.DeleteOnA, key)
+		case BisyncConflict:
+			resolution, err := resolveConflict(policy, aEntry, bEntry)
+			if err != nil {
+				return BisyncPlan{}, err
+			}
+
+			switch {
+			case resolution.RenameBoth:
+				plan.RenameBoth = append(plan.RenameBoth, key)
+			case resolution.CopyAToB:
+				plan.CopyToB = append(plan.CopyToB, key)
+			case resolution.CopyBToA:
+				plan.CopyToA = append(plan.CopyToA, key)
+			}
+		case BisyncUnchanged:
+			// nothing to do
+		}
+	}
+
+	sort.Strings(plan.CopyToB)
+	sort.Strings(plan.CopyToA)
+	sort.Strings(plan.DeleteOnA)
+	sort.Strings(plan.DeleteOnB)
+	sort.Strings(plan.RenameBoth)
+
+	return plan, nil
+}
+
+// ErrTooManyDeletes is returned when a bisync plan's delete count exceeds
+// --max-delete, aborting the run before anything destructive happens.
+type ErrTooManyDeletes struct {
+	Count, Max int
+}
+
+func (e *ErrTooManyDeletes) Error() string {
+	return fmt.Sprintf("bisync: plan would delete %d objects, exceeding --max-delete=%d; aborting", e.Count, e.Max)
+}
+
+// checkMaxDelete enforces --max-delete. A negative max disables the check.
+func checkMaxDelete(plan BisyncPlan, max int) error {
+	if max < 0 {
+		return nil
+	}
+	if plan.TotalDeletes() > max {
+		return &ErrTooManyDeletes{Count: plan.TotalDeletes(), Max: max}
+	}
+	return nil
+}
+
+// resyncState rebuilds the state file from scratch for --resync, treating
+// both sides as authoritative: every key present on either side is
+// recorded as-is (A wins when both sides have it). --resync only rebuilds
+// this bookkeeping state -- it does not reconcile content by itself, so a
+// bisync run immediately after --resync will still surface any remaining
+// A/B content differences as ordinary conflicts for --conflict to handle.
+func resyncState(a, b map[string]BisyncEntry) map[string]BisyncEntry {
+	state := make(map[string]BisyncEntry, len(a)+len(b))
+	for k, v := range a {
+		state[k] = v
+	}
+	for k, v := range b {
+		if _, ok := state[k]; !ok {
+			state[k] = v
+		}
+	}
+	return state
+}
+
+// defaultBisyncStatePath derives a stable --state-file path for the (a, b)
+// prefix pair under $XDG_CACHE_HOME/s5cmd/bisync (falling back to
+// $HOME/.cache, the same convention storage.DefaultHashCachePath uses), so
+// repeated runs of the same pair reuse the same state file without
+// requiring an explicit --state-file.
+func defaultBisyncStatePath(a, b string) (string, error) {
+	cacheDir := os.Getenv("XDG_CACHE_HOME")
+	if cacheDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		cacheDir = filepath.Join(home, ".cache")
+	}
+
+	sum := sha256.Sum256([]byte(a + "\x00" + b))
+	name := hex.EncodeToString(sum[:]) + ".jsonl"
+
+	return filepath.Join(cacheDir, "s5cmd", "bisync", name), nil
+}
+
+// localSyncSide walks every regular file under root and returns a
+// BisyncEntry map keyed by the path relative to root (using "/" as the
+// separator, regardless of OS), with Hash set to the file's MD5 digest --
+// the same identity ShouldSync's HashStrategy compares on.
+func localSyncSide(root string) (map[string]BisyncEntry, error) {
+	entries := map[string]BisyncEntry{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		hash, err := storage.FileChecksum(path, storage.ChecksumAlgorithmMD5)
+		if err != nil {
+			return err
+		}
+
+		modTime := info.ModTime()
+		entries[rel] = BisyncEntry{Key: rel, Size: info.Size(), ModTime: modTime, Hash: hash}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// copyLocalFile copies src to dst, building dst up in a temp file
+// alongside it and renaming into place, the same atomic-write approach
+// writeBisyncState uses for the state file.
+func copyLocalFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename below succeeds
+
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, dst)
+}
+
+// applyBisyncPlan performs the copies, deletes and renames plan describes
+// between the local directories rootA and rootB.
+func applyBisyncPlan(plan BisyncPlan, rootA, rootB string) error {
+	for _, key := range plan.CopyToB {
+		if err := copyLocalFile(filepath.Join(rootA, key), filepath.Join(rootB, key)); err != nil {
+			return fmt.Errorf("bisync: copy %q to destination: %w", key, err)
+		}
+	}
+	for _, key := range plan.CopyToA {
+		if err := copyLocalFile(filepath.Join(rootB, key), filepath.Join(rootA, key)); err != nil {
+			return fmt.Errorf("bisync: copy %q to source: %w", key, err)
+		}
+	}
+	for _, key := range plan.DeleteOnA {
+		if err := os.Remove(filepath.Join(rootA, key)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("bisync: delete %q on source: %w", key, err)
+		}
+	}
+	for _, key := range plan.DeleteOnB {
+		if err := os.Remove(filepath.Join(rootB, key)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("bisync: delete %q on destination: %w", key, err)
+		}
+	}
+	for _, key := range plan.RenameBoth {
+		aPath1 := filepath.Join(rootA, key+".path1")
+		bPath2 := filepath.Join(rootB, key+".path2")
+
+		if err := os.Rename(filepath.Join(rootA, key), aPath1); err != nil {
+			return fmt.Errorf("bisync: rename-both %q: %w", key, err)
+		}
+		if err := copyLocalFile(aPath1, filepath.Join(rootB, key+".path1")); err != nil {
+			return fmt.Errorf("bisync: rename-both %q: %w", key, err)
+		}
+		if err := os.Rename(filepath.Join(rootB, key), bPath2); err != nil {
+			return fmt.Errorf("bisync: rename-both %q: %w", key, err)
+		}
+		if err := copyLocalFile(bPath2, filepath.Join(rootA, key+".path2")); err != nil {
+			return fmt.Errorf("bisync: rename-both %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// runBisync drives one bisync run between the local directories rootA and
+// rootB. For --resync, it only rebuilds the state file from the current
+// listings (see resyncState) and returns -- it never plans or applies any
+// copy/delete/rename, so it can't clobber either side. Otherwise, it loads
+// the previous state, plans and applies the necessary
+// copies/deletes/renames, and persists the resulting state for the next
+// run.
+func runBisync(rootA, rootB string, policy ConflictPolicy, maxDelete int, resync bool, statePath string) error {
+	if statePath == "" {
+		var err error
+		statePath, err = defaultBisyncStatePath(rootA, rootB)
+		if err != nil {
+			return err
+		}
+	}
+
+	a, err := localSyncSide(rootA)
+	if err != nil {
+		return fmt.Errorf("bisync: listing %q: %w", rootA, err)
+	}
+	b, err := localSyncSide(rootB)
+	if err != nil {
+		return fmt.Errorf("bisync: listing %q: %w", rootB, err)
+	}
+
+	// --resync only rebuilds the bookkeeping state from the current
+	// listings (A wins on key collisions) -- it must not reconcile
+	// content itself. Feeding that rebuilt state straight into planBisync
+	// as prev would make any key that disagrees between A and B look
+	// like an ordinary single-sided modification (since prev == a for
+	// that key), so it'd get blindly copied over rather than surfaced as
+	// a conflict. See resyncState's doc comment.
+	if resync {
+		return writeBisyncState(statePath, resyncState(a, b))
+	}
+
+	prev, err := readBisyncState(statePath)
+	if err != nil {
+		return err
+	}
+
+	plan, err := planBisync(prev, a, b, policy)
+	if err != nil {
+		return err
+	}
+
+	if err := checkMaxDelete(plan, maxDelete); err != nil {
+		return err
+	}
+
+	if err := applyBisyncPlan(plan, rootA, rootB); err != nil {
+		return err
+	}
+
+	aFinal, err := localSyncSide(rootA)
+	if err != nil {
+		return fmt.Errorf("bisync: listing %q: %w", rootA, err)
+	}
+	bFinal, err := localSyncSide(rootB)
+	if err != nil {
+		return fmt.Errorf("bisync: listing %q: %w", rootB, err)
+	}
+
+	return writeBisyncState(statePath, resyncState(aFinal, bFinal))
+}
+
+// BisyncCommand keeps two prefixes mutually consistent using a persistent
+// per-pair state file. See planBisync for the classification and
+// conflict-resolution logic.
+//
+// Only local<->local prefixes are supported for now: listing and
+// transferring S3 objects needs the bucket/object storage client, which
+// this build doesn't include.
+var BisyncCommand = &cli.Command{
+	Name:      "bisync",
+	HelpName:  "bisync",
+	Usage:     "bidirectionally sync two local directories, propagating changes in both directions",
+	ArgsUsage: "source destination",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "conflict",
+			Value: string(ConflictNewer),
+			Usage: "conflict resolution policy: newer, larger, path1, path2, rename-both",
+		},
+		&cli.IntFlag{
+			Name:  "max-delete",
+			Value: -1,
+			Usage: "abort if the plan would delete more than this many objects (-1 disables the check)",
+		},
+		&cli.BoolFlag{
+			Name:  "resync",
+			Usage: "rebuild the state file from scratch, treating both sides as authoritative",
+		},
+		&cli.StringFlag{
+			Name:  "state-file",
+			Usage: "path to the bisync state file (default: derived from the two prefixes)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		srcURL, err := url.New(c.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		dstURL, err := url.New(c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+
+		if srcURL.IsRemote() || dstURL.IsRemote() {
+			return fmt.Errorf("bisync: %q <-> %q: only local<->local prefixes are supported in this build; s3:// listing/transfer is not available", srcURL, dstURL)
+		}
+
+		return runBisync(
+			srcURL.Absolute(),
+			dstURL.Absolute(),
+			ConflictPolicy(c.String("conflict")),
+			c.Int("max-delete"),
+			c.Bool("resync"),
+			c.String("state-file"),
+		)
+	},
+}
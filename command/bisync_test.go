@@ -0,0 +1,332 @@
+package command
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/assert"
+)
+
+func bisyncEntry(size int64, hash string) BisyncEntry {
+	return BisyncEntry{Size: size, ModTime: time.Unix(0, 0), Hash: hash}
+}
+
+func TestBisyncStateRoundTripsAcrossRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.jsonl")
+
+	state := map[string]BisyncEntry{
+		"a.txt": {Key: "a.txt", Size: 10, ModTime: time.Unix(100, 0), Hash: "h1"},
+		"b.txt": {Key: "b.txt", Size: 20, ModTime: time.Unix(200, 0), Hash: "h2"},
+	}
+
+	assert.NilError(t, writeBisyncState(path, state))
+
+	// Simulate a fresh process (a later bisync invocation) reading back the
+	// state written by a previous one.
+	got, err := readBisyncState(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, got["a.txt"].Hash, "h1")
+	assert.DeepEqual(t, got["b.txt"].Hash, "h2")
+}
+
+func TestReadBisyncStateMissingFileIsEmptyState(t *testing.T) {
+	state, err := readBisyncState(filepath.Join(t.TempDir(), "does-not-exist.jsonl"))
+	assert.NilError(t, err)
+	assert.Equal(t, len(state), 0)
+}
+
+func TestWriteBisyncStateLeavesNoTempFilesBehind(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.jsonl")
+
+	assert.NilError(t, writeBisyncState(path, map[string]BisyncEntry{
+		"a.txt": {Key: "a.txt", Size: 1, Hash: "h1"},
+	}))
+
+	entries, err := filepathGlob(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1, "only the final state file should remain, not the temp file")
+}
+
+func filepathGlob(dir string) ([]string, error) {
+	return filepath.Glob(filepath.Join(dir, "*"))
+}
+
+func TestClassifyBisyncKey(t *testing.T) {
+	prev := bisyncEntry(10, "h1")
+	unchangedA := bisyncEntry(10, "h1")
+	unchangedB := bisyncEntry(10, "h1")
+	modifiedA := bisyncEntry(10, "h2")
+	modifiedB := bisyncEntry(10, "h2")
+
+	testCases := []struct {
+		name     string
+		prev     BisyncEntry
+		prevOK   bool
+		a        BisyncEntry
+		aOK      bool
+		b        BisyncEntry
+		bOK      bool
+		expected BisyncAction
+	}{
+		{"unchanged", prev, true, unchangedA, true, unchangedB, true, BisyncUnchanged},
+		{"modified on A only", prev, true, modifiedA, true, unchangedB, true, BisyncModifiedOnA},
+		{"modified on B only", prev, true, unchangedA, true, modifiedB, true, BisyncModifiedOnB},
+		{"modified on both is a conflict", prev, true, modifiedA, true, modifiedB, true, BisyncConflict},
+		{"new on A", BisyncEntry{}, false, unchangedA, true, BisyncEntry{}, false, BisyncNewOnA},
+		{"new on B", BisyncEntry{}, false, BisyncEntry{}, false, unchangedB, true, BisyncNewOnB},
+		{"deleted on A", prev, true, BisyncEntry{}, false, unchangedB, true, BisyncDeletedOnA},
+		{"deleted on B", prev, true, unchangedA, true, BisyncEntry{}, false, BisyncDeletedOnB},
+		{"gone from both sides", prev, true, BisyncEntry{}, false, BisyncEntry{}, false, BisyncUnchanged},
+		{"first sync, sides already agree", BisyncEntry{}, false, unchangedA, true, unchangedB, true, BisyncUnchanged},
+		{"first sync, sides differ", BisyncEntry{}, false, modifiedA, true, unchangedB, true, BisyncConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := classifyBisyncKey(tc.prev, tc.prevOK, tc.a, tc.aOK, tc.b, tc.bOK)
+			assert.Equal(t, got, tc.expected)
+		})
+	}
+}
+
+func TestResolveConflictNewer(t *testing.T) {
+	older := BisyncEntry{ModTime: time.Unix(100, 0)}
+	newer := BisyncEntry{ModTime: time.Unix(200, 0)}
+
+	res, err := resolveConflict(ConflictNewer, newer, older)
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyAToB)
+
+	res, err = resolveConflict(ConflictNewer, older, newer)
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyBToA)
+}
+
+func TestResolveConflictLarger(t *testing.T) {
+	small := BisyncEntry{Size: 1}
+	large := BisyncEntry{Size: 100}
+
+	res, err := resolveConflict(ConflictLarger, large, small)
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyAToB)
+
+	res, err = resolveConflict(ConflictLarger, small, large)
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyBToA)
+}
+
+func TestResolveConflictPath1AndPath2(t *testing.T) {
+	res, err := resolveConflict(ConflictPath1, BisyncEntry{}, BisyncEntry{})
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyAToB)
+
+	res, err = resolveConflict(ConflictPath2, BisyncEntry{}, BisyncEntry{})
+	assert.NilError(t, err)
+	assert.Assert(t, res.CopyBToA)
+}
+
+func TestResolveConflictRenameBoth(t *testing.T) {
+	res, err := resolveConflict(ConflictRenameBoth, BisyncEntry{}, BisyncEntry{})
+	assert.NilError(t, err)
+	assert.Assert(t, res.RenameBoth)
+}
+
+func TestResolveConflictUnknownPolicy(t *testing.T) {
+	_, err := resolveConflict(ConflictPolicy("bogus"), BisyncEntry{}, BisyncEntry{})
+	assert.ErrorContains(t, err, "unknown conflict policy")
+}
+
+func TestPlanBisyncClassifiesEveryKey(t *testing.T) {
+	prev := map[string]BisyncEntry{
+		"unchanged.txt": bisyncEntry(10, "h1"),
+		"deleted-b.txt": bisyncEntry(10, "h1"),
+	}
+	a := map[string]BisyncEntry{
+		"unchanged.txt": bisyncEntry(10, "h1"),
+		"deleted-b.txt": bisyncEntry(10, "h1"),
+		"new-on-a.txt":  bisyncEntry(5, "h3"),
+	}
+	b := map[string]BisyncEntry{
+		"unchanged.txt": bisyncEntry(10, "h1"),
+		"new-on-b.txt":  bisyncEntry(5, "h4"),
+	}
+
+	plan, err := planBisync(prev, a, b, ConflictNewer)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, plan.CopyToB, []string{"new-on-a.txt"})
+	assert.DeepEqual(t, plan.CopyToA, []string{"new-on-b.txt"})
+	assert.DeepEqual(t, plan.DeleteOnA, []string{"deleted-b.txt"})
+	assert.Equal(t, len(plan.DeleteOnB), 0)
+}
+
+func TestPlanBisyncResolvesConflictsWithPolicy(t *testing.T) {
+	prev := map[string]BisyncEntry{
+		"conflict.txt": bisyncEntry(10, "h1"),
+	}
+	a := map[string]BisyncEntry{
+		"conflict.txt": {Size: 10, Hash: "h2", ModTime: time.Unix(200, 0)},
+	}
+	b := map[string]BisyncEntry{
+		"conflict.txt": {Size: 10, Hash: "h3", ModTime: time.Unix(100, 0)},
+	}
+
+	plan, err := planBisync(prev, a, b, ConflictNewer)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, plan.CopyToB, []string{"conflict.txt"})
+
+	plan, err = planBisync(prev, a, b, ConflictRenameBoth)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, plan.RenameBoth, []string{"conflict.txt"})
+}
+
+func TestCheckMaxDelete(t *testing.T) {
+	plan := BisyncPlan{DeleteOnA: []string{"a", "b"}, DeleteOnB: []string{"c"}}
+
+	assert.NilError(t, checkMaxDelete(plan, -1))
+	assert.NilError(t, checkMaxDelete(plan, 3))
+
+	err := checkMaxDelete(plan, 2)
+	var tooMany *ErrTooManyDeletes
+	assert.Assert(t, errors.As(err, &tooMany))
+	assert.Equal(t, tooMany.Count, 3)
+	assert.Equal(t, tooMany.Max, 2)
+}
+
+func TestResyncStateUnionsBothSidesPreferringA(t *testing.T) {
+	a := map[string]BisyncEntry{
+		"only-a.txt": bisyncEntry(1, "ha"),
+		"shared.txt": {Size: 10, Hash: "from-a"},
+	}
+	b := map[string]BisyncEntry{
+		"only-b.txt": bisyncEntry(2, "hb"),
+		"shared.txt": {Size: 10, Hash: "from-b"},
+	}
+
+	state := resyncState(a, b)
+	assert.Equal(t, len(state), 3)
+	assert.Equal(t, state["shared.txt"].Hash, "from-a")
+	assert.Equal(t, state["only-a.txt"].Hash, "ha")
+	assert.Equal(t, state["only-b.txt"].Hash, "hb")
+}
+
+func runBisyncCommand(t *testing.T, dirA, dirB string, extraArgs ...string) error {
+	t.Helper()
+
+	app := &cli.App{Commands: []*cli.Command{BisyncCommand}}
+	args := append([]string{"s5cmd", "bisync"}, extraArgs...)
+	args = append(args, dirA, dirB)
+	return app.Run(args)
+}
+
+func TestBisyncCommandPropagatesNewFilesInBothDirections(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	stateFile := filepath.Join(t.TempDir(), "state.jsonl")
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dirA, "only-a.txt"), []byte("from a"), 0644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dirB, "only-b.txt"), []byte("from b"), 0644))
+
+	err := runBisyncCommand(t, dirA, dirB, "--state-file", stateFile)
+	assert.NilError(t, err)
+
+	gotOnB, err := os.ReadFile(filepath.Join(dirB, "only-a.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(gotOnB), "from a")
+
+	gotOnA, err := os.ReadFile(filepath.Join(dirA, "only-b.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(gotOnA), "from b")
+}
+
+func TestBisyncCommandPropagatesDeletesOnSubsequentRun(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	stateFile := filepath.Join(t.TempDir(), "state.jsonl")
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dirA, "shared.txt"), []byte("content"), 0644))
+
+	// First run establishes the baseline state and propagates the file to B.
+	assert.NilError(t, runBisyncCommand(t, dirA, dirB, "--state-file", stateFile))
+	_, err := os.Stat(filepath.Join(dirB, "shared.txt"))
+	assert.NilError(t, err)
+
+	// Deleting on A and re-running should delete on B too.
+	assert.NilError(t, os.Remove(filepath.Join(dirA, "shared.txt")))
+	assert.NilError(t, runBisyncCommand(t, dirA, dirB, "--state-file", stateFile))
+
+	_, err = os.Stat(filepath.Join(dirB, "shared.txt"))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestBisyncCommandResolvesConflictWithRenameBoth(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	stateFile := filepath.Join(t.TempDir(), "state.jsonl")
+
+	// Both sides create the same key independently, with different
+	// content -- a first-sync conflict, since there's no prior state.
+	assert.NilError(t, os.WriteFile(filepath.Join(dirA, "conflict.txt"), []byte("version a"), 0644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dirB, "conflict.txt"), []byte("version b"), 0644))
+
+	err := runBisyncCommand(t, dirA, dirB, "--state-file", stateFile, "--conflict", "rename-both")
+	assert.NilError(t, err)
+
+	_, err = os.Stat(filepath.Join(dirA, "conflict.txt"))
+	assert.Assert(t, os.IsNotExist(err), "original conflicting key should be gone from A")
+
+	path1OnA, err := os.ReadFile(filepath.Join(dirA, "conflict.txt.path1"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(path1OnA), "version a")
+
+	path2OnB, err := os.ReadFile(filepath.Join(dirB, "conflict.txt.path2"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(path2OnB), "version b")
+
+	// Both sides should end up with both halves.
+	path1OnB, err := os.ReadFile(filepath.Join(dirB, "conflict.txt.path1"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(path1OnB), "version a")
+
+	path2OnA, err := os.ReadFile(filepath.Join(dirA, "conflict.txt.path2"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(path2OnA), "version b")
+}
+
+func TestBisyncCommandRejectsRemoteURLs(t *testing.T) {
+	dirA := t.TempDir()
+
+	err := runBisyncCommand(t, dirA, "s3://bucket/prefix")
+	assert.ErrorContains(t, err, "only local<->local prefixes are supported")
+}
+
+func TestBisyncCommandResyncNeverClobbersDivergedContent(t *testing.T) {
+	dirA, dirB := t.TempDir(), t.TempDir()
+	stateFile := filepath.Join(t.TempDir(), "state.jsonl")
+
+	assert.NilError(t, os.WriteFile(filepath.Join(dirA, "shared.txt"), []byte("version A"), 0644))
+	assert.NilError(t, os.WriteFile(filepath.Join(dirB, "shared.txt"), []byte("version B - totally different"), 0644))
+
+	err := runBisyncCommand(t, dirA, dirB, "--state-file", stateFile, "--resync")
+	assert.NilError(t, err)
+
+	// --resync only rebuilds the state file; it must never copy either
+	// side's content over the other's.
+	gotA, err := os.ReadFile(filepath.Join(dirA, "shared.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(gotA), "version A")
+
+	gotB, err := os.ReadFile(filepath.Join(dirB, "shared.txt"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(gotB), "version B - totally different")
+
+	// The rebuilt state records A's view of the key (resyncState prefers
+	// A), and no plan was ever built or applied for this run.
+	state, err := readBisyncState(stateFile)
+	assert.NilError(t, err)
+	wantHash, err := storage.FileChecksum(filepath.Join(dirA, "shared.txt"), storage.ChecksumAlgorithmMD5)
+	assert.NilError(t, err)
+	assert.Equal(t, state["shared.txt"].Hash, wantHash)
+}
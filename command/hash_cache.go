@@ -0,0 +1,114 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/peak/s5cmd/v2/storage"
+	"github.com/urfave/cli/v2"
+)
+
+// HashCacheCommand exposes maintenance operations for the persistent hash
+// cache used by --hash-cache (see storage.HashCache).
+var HashCacheCommand = &cli.Command{
+	Name:     "hash-cache",
+	HelpName: "hash-cache",
+	Usage:    "inspect and maintain the persistent hash cache",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:  "hash-cache",
+			Usage: "path to the hash cache file, or \"off\" to disable it (default: storage.DefaultHashCachePath())",
+		},
+	},
+	Subcommands: []*cli.Command{
+		hashCachePruneCommand,
+		hashCacheStatsCommand,
+		hashCacheClearCommand,
+	},
+}
+
+var hashCachePruneCommand = &cli.Command{
+	Name:  "prune",
+	Usage: "remove entries for files that no longer exist or have changed",
+	Action: func(c *cli.Context) error {
+		cache, err := openHashCacheForCLI(c)
+		if err != nil {
+			return err
+		}
+		defer cache.Close()
+
+		removed, err := cache.Prune()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("pruned %d stale entries\n", removed)
+		return nil
+	},
+}
+
+var hashCacheStatsCommand = &cli.Command{
+	Name:  "stats",
+	Usage: "show the number of entries currently in the hash cache",
+	Action: func(c *cli.Context) error {
+		cache, err := openHashCacheForCLI(c)
+		if err != nil {
+			return err
+		}
+		defer cache.Close()
+
+		count, err := cache.Stats()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d entries\n", count)
+		return nil
+	},
+}
+
+var hashCacheClearCommand = &cli.Command{
+	Name:  "clear",
+	Usage: "remove every entry from the hash cache",
+	Action: func(c *cli.Context) error {
+		cache, err := openHashCacheForCLI(c)
+		if err != nil {
+			return err
+		}
+		defer cache.Close()
+
+		return cache.Clear()
+	},
+}
+
+// resolveHashCachePath interprets the --hash-cache flag value: "off"
+// disables the cache, an empty value selects storage.DefaultHashCachePath,
+// and anything else is used as a literal path.
+func resolveHashCachePath(flagValue string) (path string, enabled bool, err error) {
+	if strings.EqualFold(flagValue, "off") {
+		return "", false, nil
+	}
+
+	if flagValue != "" {
+		return flagValue, true, nil
+	}
+
+	path, err = storage.DefaultHashCachePath()
+	if err != nil {
+		return "", false, err
+	}
+
+	return path, true, nil
+}
+
+func openHashCacheForCLI(c *cli.Context) (*storage.HashCache, error) {
+	path, enabled, err := resolveHashCachePath(c.String("hash-cache"))
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("hash-cache: cache is disabled (--hash-cache=off)")
+	}
+
+	return storage.OpenHashCache(path)
+}
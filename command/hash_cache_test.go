@@ -0,0 +1,38 @@
+package command
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"gotest.tools/v3/assert"
+)
+
+// TestHashCacheFlagIsRegistered guards against --hash-cache being accepted
+// by openHashCacheForCLI but rejected at parse time because no such flag is
+// registered on HashCacheCommand (urfave/cli errors out with "flag
+// provided but not defined" before Action ever runs).
+func TestHashCacheFlagIsRegistered(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "hashes.db")
+
+	app := &cli.App{
+		Name:     "s5cmd",
+		Commands: []*cli.Command{HashCacheCommand},
+	}
+
+	err := app.Run([]string{"s5cmd", "hash-cache", "--hash-cache=" + dbPath, "stats"})
+	assert.NilError(t, err)
+}
+
+// TestHashCacheOffRejectsRatherThanFallingBackToDefault guards against
+// --hash-cache=off silently operating on storage.DefaultHashCachePath()
+// instead of reporting that the cache is disabled.
+func TestHashCacheOffRejectsRatherThanFallingBackToDefault(t *testing.T) {
+	app := &cli.App{
+		Name:     "s5cmd",
+		Commands: []*cli.Command{HashCacheCommand},
+	}
+
+	err := app.Run([]string{"s5cmd", "hash-cache", "--hash-cache=off", "stats"})
+	assert.ErrorContains(t, err, "disabled")
+}
@@ -34,7 +34,7 @@ func BenchmarkHashCalculationWithoutCache(b *testing.B) {
 			b.Fatal(err)
 		}
 		// This will trigger MD5 calculation every time
-		_ = getHash(obj)
+		_ = getHash(obj, storage.ChecksumAlgorithmMD5)
 	}
 }
 
@@ -62,7 +62,7 @@ func BenchmarkHashCalculationWithCache(b *testing.B) {
 			b.Fatal(err)
 		}
 		// This will just return the cached ETag
-		_ = getHash(obj)
+		_ = getHash(obj, storage.ChecksumAlgorithmMD5)
 	}
 }
 
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeKey returns key's Unicode NFC normal form. macOS's filesystem
+// APIs always return NFD-decomposed names (e.g. "o" + combining diaeresis)
+// even when the file was originally uploaded with an NFC-composed name (a
+// single "ö" codepoint), while S3 preserves whatever form the uploader
+// sent -- usually NFC. Without normalizing first, sync's source/destination
+// pairing sees these as two different keys and loops forever re-uploading
+// one and deleting the other.
+func normalizeKey(key string) string {
+	return norm.NFC.String(key)
+}
+
+// sameNormalizedKey reports whether srcKey and dstKey name the same object
+// once Unicode normalization differences are accounted for.
+func sameNormalizedKey(srcKey, dstKey string) bool {
+	return normalizeKey(srcKey) == normalizeKey(dstKey)
+}
+
+// sameFoldedKey reports whether srcKey and dstKey would collide on a
+// case-insensitive (or case-folding) backend, after also normalizing
+// Unicode form.
+func sameFoldedKey(srcKey, dstKey string) bool {
+	return strings.EqualFold(normalizeKey(srcKey), normalizeKey(dstKey))
+}
+
+// KeyDifference classifies how two keys that the sync planner considers
+// "the same" object differ from each other.
+type KeyDifference int
+
+const (
+	// KeysIdentical means the keys are byte-for-byte equal.
+	KeysIdentical KeyDifference = iota
+	// KeysDifferByNormalization means the keys are equal only after NFC
+	// normalization (e.g. NFD vs NFC).
+	KeysDifferByNormalization
+	// KeysDifferByCase means the keys are equal only after case-folding
+	// and Unicode normalization.
+	KeysDifferByCase
+	// KeysDistinct means the keys refer to genuinely different objects.
+	KeysDistinct
+)
+
+// classifyKeyDifference compares srcKey and dstKey and reports how (if at
+// all) they refer to the same object.
+func classifyKeyDifference(srcKey, dstKey string) KeyDifference {
+	switch {
+	case srcKey == dstKey:
+		return KeysIdentical
+	case sameNormalizedKey(srcKey, dstKey):
+		return KeysDifferByNormalization
+	case sameFoldedKey(srcKey, dstKey):
+		return KeysDifferByCase
+	default:
+		return KeysDistinct
+	}
+}
+
+// shouldRenameInsteadOfReupload implements the --fix-case/--fix-normalization
+// decision: given the sync planner paired srcKey with an existing dstKey
+// that isn't byte-identical, it reports whether the pair should be
+// reconciled with a server-side rename (CopyObject + DeleteObject) instead
+// of a full re-upload, and whether deleting dstKey as part of that rename
+// is safe.
+//
+// Deleting is only ever considered safe when the keys are equal after NFC
+// normalization -- that's the one case we can be certain dstKey and srcKey
+// name the same underlying object on any backend, so replacing it can't
+// lose data. A case-only difference still qualifies for a rename under
+// --fix-case (it collides on a case-insensitive backend), but the delete
+// is guarded behind the same normalized-key check so a rename is never
+// turned into a data-loss delete when the backend actually is
+// case-sensitive and the "collision" was a caller mistake -- e.g. "File.txt"
+// and "file.txt" can be two genuinely distinct objects on real S3.
+func shouldRenameInsteadOfReupload(srcKey, dstKey string, fixCase, fixNormalization bool) (rename, safeToDelete bool) {
+	switch classifyKeyDifference(srcKey, dstKey) {
+	case KeysDifferByNormalization:
+		if !fixNormalization {
+			return false, false
+		}
+		return true, true
+	case KeysDifferByCase:
+		if !fixCase {
+			return false, false
+		}
+		return true, sameNormalizedKey(srcKey, dstKey)
+	default:
+		return false, false
+	}
+}
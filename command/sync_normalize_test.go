@@ -0,0 +1,89 @@
+package command
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// nfcCafe and nfdCafe both render as "café" but use different Unicode
+// normal forms: NFC is a single precomposed codepoint for "é", while NFD
+// is "e" followed by a combining acute accent -- the form macOS's
+// filesystem APIs return even when the file was uploaded with an
+// NFC-composed name.
+const (
+	nfcCafe = "café"  // "cafe\u00e9": precomposed e-acute (NFC)
+	nfdCafe = "café" // "cafe" + combining acute accent U+0301 (NFD)
+)
+
+func TestSameNormalizedKey(t *testing.T) {
+	assert.Assert(t, sameNormalizedKey(nfcCafe, nfdCafe))
+	assert.Assert(t, !sameNormalizedKey(nfcCafe, "cafe"))
+}
+
+func TestSameFoldedKey(t *testing.T) {
+	assert.Assert(t, sameFoldedKey("Prefix/File.txt", "prefix/file.txt"))
+	assert.Assert(t, sameFoldedKey(nfcCafe, "CAFÉ")) // precomposed, different case
+	assert.Assert(t, !sameFoldedKey("a.txt", "b.txt"))
+}
+
+func TestClassifyKeyDifference(t *testing.T) {
+	testCases := []struct {
+		name     string
+		srcKey   string
+		dstKey   string
+		expected KeyDifference
+	}{
+		{"identical", "a/b.txt", "a/b.txt", KeysIdentical},
+		{"normalization only", nfcCafe, nfdCafe, KeysDifferByNormalization},
+		{"case only", "a/B.txt", "a/b.txt", KeysDifferByCase},
+		{"distinct", "a/b.txt", "a/c.txt", KeysDistinct},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, classifyKeyDifference(tc.srcKey, tc.dstKey), tc.expected)
+		})
+	}
+}
+
+func TestShouldRenameInsteadOfReuploadNormalization(t *testing.T) {
+	// Disabled by default: no rename, no delete.
+	rename, safe := shouldRenameInsteadOfReupload(nfcCafe, nfdCafe, false, false)
+	assert.Assert(t, !rename)
+	assert.Assert(t, !safe)
+
+	// --fix-normalization enables a rename, and it's always safe to delete
+	// since the keys are equal after NFC normalization.
+	rename, safe = shouldRenameInsteadOfReupload(nfcCafe, nfdCafe, false, true)
+	assert.Assert(t, rename)
+	assert.Assert(t, safe)
+}
+
+func TestShouldRenameInsteadOfReuploadCaseOnly(t *testing.T) {
+	// Disabled by default.
+	rename, safe := shouldRenameInsteadOfReupload("a/File.txt", "a/file.txt", false, false)
+	assert.Assert(t, !rename)
+	assert.Assert(t, !safe)
+
+	// --fix-case enables the rename, but a pure case difference never
+	// satisfies the normalized-key equality check the delete is guarded
+	// by -- on a case-sensitive backend "File.txt" and "file.txt" can be
+	// two genuinely distinct objects, so deleting one is never assumed
+	// safe.
+	rename, safe = shouldRenameInsteadOfReupload("a/File.txt", "a/file.txt", true, false)
+	assert.Assert(t, rename)
+	assert.Assert(t, !safe)
+}
+
+func TestShouldRenameInsteadOfReuploadDistinctKeysNeverRenames(t *testing.T) {
+	rename, safe := shouldRenameInsteadOfReupload("a/one.txt", "a/two.txt", true, true)
+	assert.Assert(t, !rename)
+	assert.Assert(t, !safe)
+}
+
+func TestShouldRenameInsteadOfReuploadIdenticalKeysNeverRenames(t *testing.T) {
+	rename, safe := shouldRenameInsteadOfReupload("a/same.txt", "a/same.txt", true, true)
+	assert.Assert(t, !rename)
+	assert.Assert(t, !safe)
+}
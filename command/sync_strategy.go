@@ -0,0 +1,286 @@
+package command
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	errorpkg "github.com/peak/s5cmd/v2/error"
+	"github.com/peak/s5cmd/v2/storage"
+)
+
+// defaultMultipartChunkSizes are the part sizes tried, in order, when
+// reconstructing a local multipart ETag and no --multipart-chunk-size
+// override is configured (or the override doesn't produce the expected
+// part count). These mirror the defaults used by common S3 clients: the
+// AWS CLI/SDKs default to 8 MiB parts, while many other tools (and S3's
+// own minimum part size) use 5 MiB.
+var defaultMultipartChunkSizes = []int64{
+	8 * 1024 * 1024,
+	5 * 1024 * 1024,
+}
+
+// candidateMultipartChunkSizes returns the part sizes to try, in order,
+// when reconstructing a local multipart ETag. configured is the value of
+// --multipart-chunk-size (0 if unset); when positive, it's tried first,
+// ahead of the common-client defaults.
+func candidateMultipartChunkSizes(configured int64) []int64 {
+	if configured <= 0 {
+		return defaultMultipartChunkSizes
+	}
+	return append([]int64{configured}, defaultMultipartChunkSizes...)
+}
+
+// SyncStrategy determines whether a source object should be synced
+// (copied) to a destination object.
+type SyncStrategy interface {
+	ShouldSync(srcObject, dstObject *storage.Object) error
+}
+
+// SizeOnlyStrategy syncs an object if and only if its size differs between
+// source and destination, ignoring content and modification time entirely.
+type SizeOnlyStrategy struct{}
+
+// ShouldSync decides whether to sync based on object size.
+func (s *SizeOnlyStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+	return errorpkg.ErrObjectSizesMatch
+}
+
+// SizeAndModificationStrategy is the default sync strategy: an object is
+// synced if the source is newer than the destination, or if the sizes
+// differ.
+type SizeAndModificationStrategy struct{}
+
+// ShouldSync decides whether to sync based on modification time and size.
+func (sm *SizeAndModificationStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	if srcObj.ModTime.After(*dstObj.ModTime) {
+		return nil
+	}
+
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+
+	return errorpkg.ErrObjectIsNewerAndSizesMatch
+}
+
+// HashStrategy syncs an object if its hash differs from its counterpart's,
+// regardless of modification time.
+type HashStrategy struct {
+	// Algorithm selects the checksum algorithm used to compare objects. The
+	// zero value (and ChecksumAlgorithmMD5) compares against the MD5-based
+	// ETag and additionally reconstructs multipart ETags locally when
+	// needed; any other algorithm compares the matching x-amz-checksum-*
+	// value directly, with no multipart reconstruction.
+	Algorithm storage.ChecksumAlgorithm
+
+	// MultipartChunkSize is set from --multipart-chunk-size. When
+	// reconstructing a local multipart ETag (MD5 comparison only), it's
+	// tried as the part size before falling back to the common-client
+	// defaults. Zero means try only those defaults.
+	MultipartChunkSize int64
+}
+
+// ShouldSync decides whether to sync based on content hash.
+func (h *HashStrategy) ShouldSync(srcObj, dstObj *storage.Object) error {
+	if srcObj.Size != dstObj.Size {
+		return nil
+	}
+
+	algo := h.Algorithm
+	if algo == "" {
+		algo = storage.ChecksumAlgorithmMD5
+	}
+
+	if algo != storage.ChecksumAlgorithmMD5 {
+		srcSum, dstSum := getHash(srcObj, algo), getHash(dstObj, algo)
+		if srcSum != "" && dstSum != "" && srcSum == dstSum {
+			return errorpkg.ErrObjectEtagsMatch
+		}
+		return nil
+	}
+
+	srcMultipart := isMultipartETag(srcObj.Etag)
+	dstMultipart := isMultipartETag(dstObj.Etag)
+
+	if srcMultipart || dstMultipart {
+		return h.shouldSyncMultipart(srcObj, dstObj, srcMultipart, dstMultipart)
+	}
+
+	if getHash(srcObj, algo) == getHash(dstObj, algo) {
+		return errorpkg.ErrObjectEtagsMatch
+	}
+
+	return nil
+}
+
+// shouldSyncMultipart handles the case where at least one side reports a
+// multipart-style ETag ("<hex>-N"). Such ETags cannot be compared directly
+// against a plain MD5 digest, so whichever side is a local file is
+// re-hashed in parts to reconstruct the equivalent multipart ETag. If
+// neither side is a local file we can re-hash (e.g. both are remote
+// objects), there's no way to verify equality, so we fall back to the
+// conservative behavior of always syncing.
+func (h *HashStrategy) shouldSyncMultipart(srcObj, dstObj *storage.Object, srcMultipart, dstMultipart bool) error {
+	if dstMultipart && !srcObj.URL.IsRemote() {
+		if reconstructed := reconstructMultipartHash(srcObj, dstObj.Etag, h.MultipartChunkSize); reconstructed != "" {
+			if reconstructed == dstObj.Etag {
+				return errorpkg.ErrObjectEtagsMatch
+			}
+			return nil
+		}
+	}
+
+	if srcMultipart && !dstObj.URL.IsRemote() {
+		if reconstructed := reconstructMultipartHash(dstObj, srcObj.Etag, h.MultipartChunkSize); reconstructed != "" {
+			if reconstructed == srcObj.Etag {
+				return errorpkg.ErrObjectEtagsMatch
+			}
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// isMultipartETag reports whether etag looks like an S3 multipart ETag,
+// i.e. "<hex-md5-of-parts>-<part-count>" rather than a plain MD5 digest.
+func isMultipartETag(etag string) bool {
+	return strings.Contains(etag, "-")
+}
+
+// multipartPartCount extracts the part count encoded in a multipart ETag.
+// It returns ok=false if etag is not a multipart ETag or the suffix isn't a
+// valid positive integer.
+func multipartPartCount(etag string) (int, bool) {
+	if !isMultipartETag(etag) {
+		return 0, false
+	}
+
+	idx := strings.LastIndex(etag, "-")
+	n, err := strconv.Atoi(etag[idx+1:])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// reconstructMultipartHash attempts to derive the multipart ETag that
+// localObj would produce if it were uploaded using the same number of
+// parts encoded in remoteEtag. It tries each of
+// candidateMultipartChunkSizes(configuredChunkSize) until one produces the
+// expected part count, and returns the matching reconstructed ETag, or ""
+// if none matched or localObj isn't a local file. On success, the
+// reconstructed ETag is cached onto localObj.Etag so subsequent
+// comparisons don't repeat the chunked hashing.
+func reconstructMultipartHash(localObj *storage.Object, remoteEtag string, configuredChunkSize int64) string {
+	if localObj.URL.IsRemote() {
+		return ""
+	}
+
+	partCount, ok := multipartPartCount(remoteEtag)
+	if !ok {
+		return ""
+	}
+
+	for _, partSize := range candidateMultipartChunkSizes(configuredChunkSize) {
+		expectedParts := int((localObj.Size + partSize - 1) / partSize)
+		if expectedParts != partCount {
+			continue
+		}
+
+		etag, err := localMultipartETag(localObj.URL.Absolute(), partCount, partSize)
+		if err != nil {
+			continue
+		}
+
+		localObj.Etag = etag
+		return etag
+	}
+
+	return ""
+}
+
+// localMultipartETag reconstructs the ETag S3 would assign to a multipart
+// upload of the file at path split into partCount equal-sized parts of
+// partSize bytes (the last part may be shorter): each part is MD5'd, the
+// raw digests are concatenated, the concatenation is MD5'd again, and the
+// part count is appended as "-N".
+func localMultipartETag(path string, partCount int, partSize int64) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var concatenated []byte
+	buf := make([]byte, partSize)
+
+	for i := 0; i < partCount; i++ {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return "", err
+		}
+
+		sum := md5.Sum(buf[:n])
+		concatenated = append(concatenated, sum[:]...)
+	}
+
+	finalSum := md5.Sum(concatenated)
+	return hex.EncodeToString(finalSum[:]) + "-" + strconv.Itoa(partCount), nil
+}
+
+// getHash returns obj's checksum under algo, suitable for comparing against
+// its counterpart. For algo == ChecksumAlgorithmMD5, remote objects already
+// carry their hash in Etag; for other algorithms, remote objects carry it
+// in Checksums, as populated from S3's x-amz-checksum-* headers. Local
+// files are hashed on demand if the value isn't already cached on obj.
+func getHash(obj *storage.Object, algo storage.ChecksumAlgorithm) string {
+	if algo == "" {
+		algo = storage.ChecksumAlgorithmMD5
+	}
+
+	if algo == storage.ChecksumAlgorithmMD5 {
+		if obj.Etag != "" || obj.URL.IsRemote() {
+			return obj.Etag
+		}
+	} else {
+		if sum, ok := obj.Checksums[string(algo)]; ok && sum != "" {
+			return sum
+		}
+		if obj.URL.IsRemote() {
+			return ""
+		}
+	}
+
+	sum, err := storage.FileChecksum(obj.URL.Absolute(), algo)
+	if err != nil {
+		return ""
+	}
+
+	return sum
+}
+
+// NewStrategy returns the SyncStrategy selected by the given flags.
+// sizeOnly takes precedence over hashOnly; if neither is set, sync falls
+// back to comparing size and modification time. checksumAlgorithm and
+// multipartChunkSize (from --multipart-chunk-size) are only meaningful
+// when hashOnly is set.
+func NewStrategy(sizeOnly, hashOnly bool, checksumAlgorithm storage.ChecksumAlgorithm, multipartChunkSize int64) SyncStrategy {
+	if sizeOnly {
+		return &SizeOnlyStrategy{}
+	}
+
+	if hashOnly {
+		return &HashStrategy{Algorithm: checksumAlgorithm, MultipartChunkSize: multipartChunkSize}
+	}
+
+	return &SizeAndModificationStrategy{}
+}
@@ -3,9 +3,11 @@ package command
 import (
 	"context"
 	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -170,7 +172,7 @@ func TestGetHashWithRemoteObject(t *testing.T) {
 		Etag: "remote-etag",
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, "remote-etag")
 }
 
@@ -182,7 +184,7 @@ func TestGetHashWithLocalFileEtag(t *testing.T) {
 		Etag: "existing-etag",
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, "existing-etag")
 }
 
@@ -207,7 +209,7 @@ func TestGetHashWithLocalFile(t *testing.T) {
 		Size: int64(len(content)),
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, expectedHash)
 }
 
@@ -233,7 +235,7 @@ func TestGetHashWithLargeFile(t *testing.T) {
 		Size: int64(len(content)),
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, expectedHash)
 }
 
@@ -246,26 +248,26 @@ func TestGetHashWithNonExistentFile(t *testing.T) {
 		Size: 100,
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, "") // Should return empty string on error
 }
 
 func TestNewStrategy(t *testing.T) {
 	// Test creating different strategies
-	sizeOnly := NewStrategy(true, false)
+	sizeOnly := NewStrategy(true, false, "", 0)
 	_, ok := sizeOnly.(*SizeOnlyStrategy)
 	assert.Assert(t, ok)
 
-	hashOnly := NewStrategy(false, true)
+	hashOnly := NewStrategy(false, true, "", 0)
 	_, ok = hashOnly.(*HashStrategy)
 	assert.Assert(t, ok)
 
-	sizeAndMod := NewStrategy(false, false)
+	sizeAndMod := NewStrategy(false, false, "", 0)
 	_, ok = sizeAndMod.(*SizeAndModificationStrategy)
 	assert.Assert(t, ok)
 
 	// Test priority: sizeOnly takes precedence over hashOnly
-	sizeOnlyPriority := NewStrategy(true, true)
+	sizeOnlyPriority := NewStrategy(true, true, "", 0)
 	_, ok = sizeOnlyPriority.(*SizeOnlyStrategy)
 	assert.Assert(t, ok)
 }
@@ -323,14 +325,91 @@ func TestHashCachingPerformanceOptimization(t *testing.T) {
 
 	// This means getHash() for cached objects will be much faster
 	// since it just returns the pre-computed ETag instead of recalculating
-	hash1 := getHash(dstObjWithoutCache) // This will recalculate MD5
-	hash2 := getHash(dstObjWithCache)    // This will just return the ETag
+	hash1 := getHash(dstObjWithoutCache, storage.ChecksumAlgorithmMD5) // This will recalculate MD5
+	hash2 := getHash(dstObjWithCache, storage.ChecksumAlgorithmMD5)    // This will just return the ETag
 
 	assert.Equal(t, hash1, expectedHash)
 	assert.Equal(t, hash2, expectedHash)
 	t.Log("Performance optimization: hash caching avoids repeated MD5 calculations")
 }
 
+func TestHashCachingPersistsAcrossProcessRestarts(t *testing.T) {
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "testfile.txt")
+	content := "Hello, World! This persists across restarts."
+	err := os.WriteFile(localFile, []byte(content), 0644)
+	assert.NilError(t, err)
+
+	md5Hash := md5.Sum([]byte(content))
+	expectedHash := hex.EncodeToString(md5Hash[:])
+
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	localURL, _ := url.New(localFile)
+	ctx := context.Background()
+
+	// Cold run: nothing cached yet, so the file is hashed and the result is
+	// written back to the on-disk cache.
+	coldCache, err := storage.OpenHashCache(dbPath)
+	assert.NilError(t, err)
+
+	fsCold := storage.NewLocalClient(storage.Options{CacheHashes: true, HashCache: coldCache})
+	coldObj, err := fsCold.Stat(ctx, localURL)
+	assert.NilError(t, err)
+	assert.Equal(t, coldObj.Etag, expectedHash)
+	assert.NilError(t, coldCache.Close())
+
+	// Warm run: simulate a new process by reopening the cache database
+	// from scratch; the digest stored by the cold run must still be found,
+	// and Stat must report it.
+	warmCache, err := storage.OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer warmCache.Close()
+
+	entryBefore, ok := warmCache.Lookup(localFile, mustStat(t, localFile))
+	assert.Assert(t, ok)
+	assert.Equal(t, entryBefore.Digest, expectedHash)
+
+	fsWarm := storage.NewLocalClient(storage.Options{CacheHashes: true, HashCache: warmCache})
+	warmObj, err := fsWarm.Stat(ctx, localURL)
+	assert.NilError(t, err)
+	assert.Equal(t, warmObj.Etag, expectedHash)
+}
+
+func TestHashCachingInvalidatedAfterFileModification(t *testing.T) {
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "testfile.txt")
+	err := os.WriteFile(localFile, []byte("original content"), 0644)
+	assert.NilError(t, err)
+
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	cache, err := storage.OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	localURL, _ := url.New(localFile)
+	ctx := context.Background()
+	fs := storage.NewLocalClient(storage.Options{CacheHashes: true, HashCache: cache})
+
+	firstObj, err := fs.Stat(ctx, localURL)
+	assert.NilError(t, err)
+
+	// Modify the file's content (and therefore its size and mtime).
+	err = os.WriteFile(localFile, []byte("modified content, different length"), 0644)
+	assert.NilError(t, err)
+
+	secondObj, err := fs.Stat(ctx, localURL)
+	assert.NilError(t, err)
+
+	assert.Assert(t, firstObj.Etag != secondObj.Etag, "modified file must not reuse the stale cached hash")
+}
+
+func mustStat(t *testing.T, path string) os.FileInfo {
+	t.Helper()
+	fi, err := os.Stat(path)
+	assert.NilError(t, err)
+	return fi
+}
+
 func TestHashStrategyBugReproduction(t *testing.T) {
 	// This test reproduces the real-world scenario that causes the bug
 	// Let's simulate how objects are actually created in sync operation
@@ -372,8 +451,8 @@ func TestHashStrategyBugReproduction(t *testing.T) {
 	// Now test with real objects as they would be created
 	t.Logf("Source ETag: '%s'", srcObj.Etag)
 	t.Logf("Destination ETag: '%s'", dstObj.Etag)
-	t.Logf("Source hash from getHash(): '%s'", getHash(srcObj))
-	t.Logf("Destination hash from getHash(): '%s'", getHash(dstObj))
+	t.Logf("Source hash from getHash(): '%s'", getHash(srcObj, storage.ChecksumAlgorithmMD5))
+	t.Logf("Destination hash from getHash(): '%s'", getHash(dstObj, storage.ChecksumAlgorithmMD5))
 
 	err = strategy.ShouldSync(srcObj, dstObj)
 	if err == errorpkg.ErrObjectEtagsMatch {
@@ -424,8 +503,8 @@ func TestHashStrategyRemoteToLocal(t *testing.T) {
 		t.Log("Files with identical content correctly identified as not needing sync")
 	} else if err == nil {
 		t.Log("BUG: Files with identical content incorrectly marked for sync")
-		t.Log("srcHash:", getHash(srcObj))
-		t.Log("dstHash:", getHash(dstObj))
+		t.Log("srcHash:", getHash(srcObj, storage.ChecksumAlgorithmMD5))
+		t.Log("dstHash:", getHash(dstObj, storage.ChecksumAlgorithmMD5))
 		t.Fail()
 	} else {
 		t.Logf("Unexpected error: %v", err)
@@ -457,6 +536,247 @@ func TestHashStrategyWithEmptyFiles(t *testing.T) {
 	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
 }
 
+// buildMultipartETag computes the ETag S3 would assign to content if it
+// were uploaded as a multipart upload split into equal-sized parts of
+// partSize bytes (the last part may be shorter).
+func buildMultipartETag(content []byte, partSize int64) string {
+	var concatenated []byte
+	partCount := 0
+
+	for offset := int64(0); offset < int64(len(content)); offset += partSize {
+		end := offset + partSize
+		if end > int64(len(content)) {
+			end = int64(len(content))
+		}
+		sum := md5.Sum(content[offset:end])
+		concatenated = append(concatenated, sum[:]...)
+		partCount++
+	}
+
+	finalSum := md5.Sum(concatenated)
+	return hex.EncodeToString(finalSum[:]) + "-" + strconv.Itoa(partCount)
+}
+
+func TestHashStrategyReconstructsLocalMultipartETag(t *testing.T) {
+	strategy := &HashStrategy{}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "multipart.bin")
+
+	const partSize = 5 * 1024 * 1024
+	content := make([]byte, partSize*2+1024) // two full parts + a short final part
+	for i := range content {
+		content[i] = byte(i)
+	}
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	remoteEtag := buildMultipartETag(content, partSize)
+
+	remoteURL, _ := url.New("s3://bucket/multipart.bin")
+	srcObj := &storage.Object{URL: remoteURL, Size: int64(len(content)), Etag: remoteEtag}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: int64(len(content)), Etag: ""}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
+
+	// The reconstructed ETag should have been cached onto the local object.
+	assert.Equal(t, dstObj.Etag, remoteEtag)
+}
+
+func TestHashStrategyReconstructsLocalMultipartETagReverseDirection(t *testing.T) {
+	strategy := &HashStrategy{}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "multipart.bin")
+
+	const partSize = 8 * 1024 * 1024
+	content := make([]byte, partSize+42)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	remoteEtag := buildMultipartETag(content, partSize)
+
+	localURL, _ := url.New(localFile)
+	srcObj := &storage.Object{URL: localURL, Size: int64(len(content)), Etag: ""}
+
+	remoteURL, _ := url.New("s3://bucket/multipart.bin")
+	dstObj := &storage.Object{URL: remoteURL, Size: int64(len(content)), Etag: remoteEtag}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
+	assert.Equal(t, srcObj.Etag, remoteEtag)
+}
+
+func TestHashStrategyReconstructsLocalMultipartETagWithConfiguredChunkSize(t *testing.T) {
+	// A part size outside the common-client defaults (8 MiB/5 MiB) can
+	// only be reconstructed if MultipartChunkSize is consulted.
+	const partSize = 16 * 1024 * 1024
+	strategy := &HashStrategy{MultipartChunkSize: partSize}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "multipart.bin")
+
+	content := make([]byte, partSize+1024)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	remoteEtag := buildMultipartETag(content, partSize)
+
+	remoteURL, _ := url.New("s3://bucket/multipart.bin")
+	srcObj := &storage.Object{URL: remoteURL, Size: int64(len(content)), Etag: remoteEtag}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: int64(len(content)), Etag: ""}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
+	assert.Equal(t, dstObj.Etag, remoteEtag)
+}
+
+func TestHashStrategyMultipartMismatchStillSyncs(t *testing.T) {
+	strategy := &HashStrategy{}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "multipart.bin")
+
+	content := make([]byte, 5*1024*1024+10)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	remoteURL, _ := url.New("s3://bucket/multipart.bin")
+	srcObj := &storage.Object{
+		URL:  remoteURL,
+		Size: int64(len(content)),
+		Etag: "deadbeefdeadbeefdeadbeefdeadbeef-2",
+	}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: int64(len(content)), Etag: ""}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.NilError(t, err)
+}
+
+func TestHashStrategyWithSHA256Algorithm(t *testing.T) {
+	strategy := &HashStrategy{Algorithm: storage.ChecksumAlgorithmSHA256}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "testfile.txt")
+	content := "Hello, World!"
+	err := os.WriteFile(localFile, []byte(content), 0644)
+	assert.NilError(t, err)
+
+	expectedHash, err := storage.FileChecksum(localFile, storage.ChecksumAlgorithmSHA256)
+	assert.NilError(t, err)
+
+	remoteURL, _ := url.New("s3://bucket/testfile.txt")
+	srcObj := &storage.Object{
+		URL:       remoteURL,
+		Size:      int64(len(content)),
+		Checksums: map[string]string{"sha256": expectedHash},
+	}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: int64(len(content))}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
+
+	// A multipart-style MD5 ETag must not short-circuit the comparison when
+	// a non-MD5 algorithm was explicitly requested.
+	srcObj.Etag = "deadbeefdeadbeefdeadbeefdeadbeef-3"
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.Equal(t, err, errorpkg.ErrObjectEtagsMatch)
+}
+
+func TestHashStrategyWithCRC32CAlgorithmMismatch(t *testing.T) {
+	strategy := &HashStrategy{Algorithm: storage.ChecksumAlgorithmCRC32C}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "testfile.txt")
+	err := os.WriteFile(localFile, []byte("Hello, World!"), 0644)
+	assert.NilError(t, err)
+
+	remoteURL, _ := url.New("s3://bucket/testfile.txt")
+	srcObj := &storage.Object{
+		URL:       remoteURL,
+		Size:      13,
+		Checksums: map[string]string{"crc32c": "not-the-right-checksum"},
+	}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: 13}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.NilError(t, err)
+}
+
+func TestHashStrategyWithMissingRemoteChecksumAlwaysSyncs(t *testing.T) {
+	strategy := &HashStrategy{Algorithm: storage.ChecksumAlgorithmCRC64NVME}
+
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "testfile.txt")
+	err := os.WriteFile(localFile, []byte("Hello, World!"), 0644)
+	assert.NilError(t, err)
+
+	// Remote object has no crc64nvme checksum recorded, so there's nothing
+	// to compare against.
+	remoteURL, _ := url.New("s3://bucket/testfile.txt")
+	srcObj := &storage.Object{URL: remoteURL, Size: 13}
+
+	localURL, _ := url.New(localFile)
+	dstObj := &storage.Object{URL: localURL, Size: 13}
+
+	err = strategy.ShouldSync(srcObj, dstObj)
+	assert.NilError(t, err)
+}
+
+func TestFileChecksumAlgorithms(t *testing.T) {
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "testfile")
+	content := "Hello, World!"
+	err := os.WriteFile(tmpFile, []byte(content), 0644)
+	assert.NilError(t, err)
+
+	for _, algo := range []storage.ChecksumAlgorithm{
+		storage.ChecksumAlgorithmMD5,
+		storage.ChecksumAlgorithmSHA256,
+		storage.ChecksumAlgorithmCRC32C,
+		storage.ChecksumAlgorithmCRC64NVME,
+	} {
+		t.Run(string(algo), func(t *testing.T) {
+			sum, err := storage.FileChecksum(tmpFile, algo)
+			assert.NilError(t, err)
+			assert.Assert(t, sum != "")
+
+			// Checksums must be deterministic.
+			sum2, err := storage.FileChecksum(tmpFile, algo)
+			assert.NilError(t, err)
+			assert.Equal(t, sum, sum2)
+		})
+	}
+}
+
+func TestFileChecksumCRC64NVMEKnownVector(t *testing.T) {
+	// "123456789" is the standard reveng CRC catalogue check value for
+	// CRC-64/NVME, with published result 0xae8b14860a799888. This guards
+	// against the algorithm producing a self-consistent but wrong value
+	// that would never match a real S3 x-amz-checksum-crc64nvme header.
+	tmpDir := t.TempDir()
+	tmpFile := filepath.Join(tmpDir, "testfile")
+	err := os.WriteFile(tmpFile, []byte("123456789"), 0644)
+	assert.NilError(t, err)
+
+	sum, err := storage.FileChecksum(tmpFile, storage.ChecksumAlgorithmCRC64NVME)
+	assert.NilError(t, err)
+
+	want := base64.StdEncoding.EncodeToString([]byte{0xae, 0x8b, 0x14, 0x86, 0x0a, 0x79, 0x98, 0x88})
+	assert.Equal(t, sum, want)
+}
+
 func TestGetHashWithFileReadError(t *testing.T) {
 	// Create a temporary file and then remove it to simulate read error
 	tmpDir := t.TempDir()
@@ -478,6 +798,6 @@ func TestGetHashWithFileReadError(t *testing.T) {
 		Size: int64(len(content)),
 	}
 
-	hash := getHash(obj)
+	hash := getHash(obj, storage.ChecksumAlgorithmMD5)
 	assert.Equal(t, hash, "") // Should return empty string on file access error
 }
@@ -0,0 +1,82 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// HeadObjectFunc fetches the current ETag of a just-uploaded key. It's
+// injected into VerifyMultipartUpload so upload verification can be unit
+// tested without a real S3 client.
+type HeadObjectFunc func(ctx context.Context) (etag string, err error)
+
+// DeleteObjectFunc removes a just-uploaded key. It's injected for the same
+// reason as HeadObjectFunc.
+type DeleteObjectFunc func(ctx context.Context) error
+
+// ErrUploadIntegrityMismatch is returned by VerifyMultipartUpload when a
+// verified upload's remote ETag doesn't match the ETag reconstructed from
+// the local parts that were uploaded.
+type ErrUploadIntegrityMismatch struct {
+	Key      string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrUploadIntegrityMismatch) Error() string {
+	return fmt.Sprintf("upload integrity check failed for %q: expected ETag %q, got %q", e.Key, e.Expected, e.Actual)
+}
+
+// VerifyMultipartUpload is run after a multipart PUT completes, behind
+// --verify-uploads. It re-fetches the uploaded object's ETag via head and
+// compares it against the ETag reconstructed from localPath's parts. A
+// mismatch means the upload was silently corrupted (e.g. by a
+// concurrent-multipart-upload race) even though S3 reported success.
+//
+// On mismatch, the corrupt object is removed via del and
+// *ErrUploadIntegrityMismatch is returned; the caller decides whether to
+// retry the upload (see --retry-on-integrity-failure / ShouldRetryUpload).
+func VerifyMultipartUpload(
+	ctx context.Context,
+	key, localPath string,
+	partCount int,
+	partSize int64,
+	head HeadObjectFunc,
+	del DeleteObjectFunc,
+) error {
+	expected, err := localMultipartETag(localPath, partCount, partSize)
+	if err != nil {
+		return err
+	}
+
+	actual, err := head(ctx)
+	if err != nil {
+		return err
+	}
+
+	if actual == expected {
+		return nil
+	}
+
+	mismatch := &ErrUploadIntegrityMismatch{Key: key, Expected: expected, Actual: actual}
+
+	if err := del(ctx); err != nil {
+		return fmt.Errorf("%w (and failed to remove the corrupt object: %v)", mismatch, err)
+	}
+
+	return mismatch
+}
+
+// ShouldRetryUpload reports whether an upload that failed with err should
+// be retried, based on --retry-on-integrity-failure. It only ever returns
+// true for integrity failures; any other error is the caller's to handle
+// through its normal retry logic.
+func ShouldRetryUpload(err error, retryOnIntegrityFailure bool) bool {
+	if !retryOnIntegrityFailure {
+		return false
+	}
+
+	var mismatch *ErrUploadIntegrityMismatch
+	return errors.As(err, &mismatch)
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestVerifyMultipartUploadSucceedsOnMatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "upload.bin")
+
+	const partSize = 5 * 1024 * 1024
+	content := make([]byte, partSize+1024)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	expectedEtag, err := localMultipartETag(localFile, 2, partSize)
+	assert.NilError(t, err)
+
+	deleted := false
+	head := func(ctx context.Context) (string, error) { return expectedEtag, nil }
+	del := func(ctx context.Context) error { deleted = true; return nil }
+
+	err = VerifyMultipartUpload(context.Background(), "bucket/upload.bin", localFile, 2, partSize, head, del)
+	assert.NilError(t, err)
+	assert.Assert(t, !deleted, "a verified upload must not be deleted")
+}
+
+func TestVerifyMultipartUploadDeletesCorruptObjectOnMismatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "upload.bin")
+
+	const partSize = 5 * 1024 * 1024
+	content := make([]byte, partSize+1024)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	deleted := false
+	head := func(ctx context.Context) (string, error) {
+		// Simulate the object server reporting a corrupted upload's ETag.
+		return "deadbeefdeadbeefdeadbeefdeadbeef-2", nil
+	}
+	del := func(ctx context.Context) error { deleted = true; return nil }
+
+	err = VerifyMultipartUpload(context.Background(), "bucket/upload.bin", localFile, 2, partSize, head, del)
+
+	var mismatch *ErrUploadIntegrityMismatch
+	assert.Assert(t, errors.As(err, &mismatch))
+	assert.Equal(t, mismatch.Key, "bucket/upload.bin")
+	assert.Assert(t, deleted, "a corrupt upload must be deleted")
+}
+
+func TestVerifyMultipartUploadReportsDeleteFailure(t *testing.T) {
+	tmpDir := t.TempDir()
+	localFile := filepath.Join(tmpDir, "upload.bin")
+
+	const partSize = 5 * 1024 * 1024
+	content := make([]byte, partSize+1024)
+	err := os.WriteFile(localFile, content, 0644)
+	assert.NilError(t, err)
+
+	head := func(ctx context.Context) (string, error) {
+		return "deadbeefdeadbeefdeadbeefdeadbeef-2", nil
+	}
+	delErr := errors.New("access denied")
+	del := func(ctx context.Context) error { return delErr }
+
+	err = VerifyMultipartUpload(context.Background(), "bucket/upload.bin", localFile, 2, partSize, head, del)
+	assert.ErrorContains(t, err, "access denied")
+
+	var mismatch *ErrUploadIntegrityMismatch
+	assert.Assert(t, errors.As(err, &mismatch))
+}
+
+func TestShouldRetryUpload(t *testing.T) {
+	mismatch := &ErrUploadIntegrityMismatch{Key: "k", Expected: "a", Actual: "b"}
+	otherErr := errors.New("network timeout")
+
+	assert.Assert(t, ShouldRetryUpload(mismatch, true))
+	assert.Assert(t, !ShouldRetryUpload(mismatch, false))
+	assert.Assert(t, !ShouldRetryUpload(otherErr, true))
+}
@@ -0,0 +1,21 @@
+// Package error defines sentinel errors shared across s5cmd packages,
+// mostly used to signal "no-op" outcomes from sync strategies rather than
+// genuine failures.
+package error
+
+import "errors"
+
+var (
+	// ErrObjectSizesMatch is returned when two objects being compared have
+	// the same size and the active strategy considers that sufficient to
+	// skip the sync.
+	ErrObjectSizesMatch = errors.New("object size matches")
+
+	// ErrObjectIsNewerAndSizesMatch is returned when the destination object
+	// is newer than or same age as the source and both have the same size.
+	ErrObjectIsNewerAndSizesMatch = errors.New("object is newer or same age and size matches")
+
+	// ErrObjectEtagsMatch is returned when two objects being compared have
+	// matching hashes (ETags).
+	ErrObjectEtagsMatch = errors.New("object etag matches")
+)
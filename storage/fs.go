@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"os"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// Options configures the behavior of storage clients.
+type Options struct {
+	// CacheHashes enables caching a file's hash at Stat time, so strategies
+	// that need it (e.g. HashStrategy) don't have to re-read and re-hash
+	// the file themselves.
+	CacheHashes bool
+
+	// ChecksumAlgorithm selects which algorithm CacheHashes uses. Empty (or
+	// ChecksumAlgorithmMD5) caches into Object.Etag; any other algorithm is
+	// cached into Object.Checksums, keyed by algorithm name.
+	ChecksumAlgorithm ChecksumAlgorithm
+
+	// HashCache, if set, is consulted before hashing a file and updated
+	// after a fresh hash is computed, so the cost of CacheHashes is paid
+	// once per (path, size, mtime, inode) rather than once per process.
+	HashCache *HashCache
+}
+
+// Filesystem is a Storage implementation backed by the local filesystem.
+type Filesystem struct {
+	opts Options
+}
+
+// NewLocalClient creates a Filesystem client configured with opts.
+func NewLocalClient(opts Options) *Filesystem {
+	return &Filesystem{opts: opts}
+}
+
+// Stat returns the Object metadata for the local file at u.
+func (f *Filesystem) Stat(ctx context.Context, u *url.URL) (*Object, error) {
+	fi, err := os.Stat(u.Absolute())
+	if err != nil {
+		return nil, err
+	}
+
+	modTime := fi.ModTime()
+	obj := &Object{
+		URL:     u,
+		Size:    fi.Size(),
+		ModTime: &modTime,
+	}
+
+	if f.opts.CacheHashes {
+		algo := f.opts.ChecksumAlgorithm
+		if algo == "" {
+			algo = ChecksumAlgorithmMD5
+		}
+
+		path := u.Absolute()
+
+		sum, cached := "", false
+		if f.opts.HashCache != nil {
+			if entry, ok := f.opts.HashCache.Lookup(path, fi); ok && entry.Algorithm == string(algo) {
+				sum, cached = entry.Digest, true
+			}
+		}
+
+		if !cached {
+			var err error
+			sum, err = FileChecksum(path, algo)
+			if err != nil {
+				return nil, err
+			}
+
+			if f.opts.HashCache != nil {
+				if err := f.opts.HashCache.Store(path, fi, string(algo), sum); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if algo == ChecksumAlgorithmMD5 {
+			obj.Etag = sum
+		} else {
+			obj.Checksums = map[string]string{string(algo): sum}
+		}
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var hashCacheBucket = []byte("hashes")
+
+// HashCacheEntry is a persisted checksum, keyed by the file's identity at
+// the time it was computed.
+type HashCacheEntry struct {
+	Algorithm  string    `json:"algorithm"`
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	ModTimeNs  int64     `json:"mod_time_ns"`
+	Inode      uint64    `json:"inode"`
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// HashCache is a persistent, on-disk cache mapping a local file's absolute
+// path to the last checksum computed for it, along with the
+// (size, mtime, inode) tuple the checksum was computed from. Keeping the
+// cache on disk lets repeated sync runs over the same large tree skip
+// re-hashing files that haven't changed since the last run.
+type HashCache struct {
+	db *bolt.DB
+	mu sync.Mutex
+}
+
+// DefaultHashCachePath returns $XDG_CACHE_HOME/s5cmd/hashes.db, falling
+// back to $HOME/.cache/s5cmd/hashes.db if XDG_CACHE_HOME is unset.
+func DefaultHashCachePath() (string, error) {
+	dir := os.Getenv("XDG_CACHE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".cache")
+	}
+
+	return filepath.Join(dir, "s5cmd", "hashes.db"), nil
+}
+
+// OpenHashCache opens (creating if necessary) the hash cache database at
+// path.
+func OpenHashCache(path string) (*HashCache, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(hashCacheBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &HashCache{db: db}, nil
+}
+
+// Close releases the underlying database file.
+func (c *HashCache) Close() error {
+	return c.db.Close()
+}
+
+// Lookup returns the cached entry for path, if one exists and its
+// (size, mtime, inode) still matches fi. A stale entry (the file changed
+// since it was cached) is never returned.
+func (c *HashCache) Lookup(path string, fi os.FileInfo) (HashCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var entry HashCacheEntry
+	var found bool
+
+	_ = c.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(hashCacheBucket).Get([]byte(path))
+		if v == nil {
+			return nil
+		}
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return nil
+		}
+		found = true
+		return nil
+	})
+
+	if !found || !identityMatches(entry, fi) {
+		return HashCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// Store writes (or overwrites) the cache entry for path.
+func (c *HashCache) Store(path string, fi os.FileInfo, algorithm, digest string) error {
+	size, modTimeNs, inode := fileIdentity(fi)
+
+	entry := HashCacheEntry{
+		Algorithm:  algorithm,
+		Digest:     digest,
+		Size:       size,
+		ModTimeNs:  modTimeNs,
+		Inode:      inode,
+		ComputedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(hashCacheBucket).Put([]byte(path), data)
+	})
+}
+
+// Prune removes entries whose file is missing, or whose identity no longer
+// matches what's on disk, and returns the number of entries removed.
+func (c *HashCache) Prune() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(hashCacheBucket)
+
+		var stale [][]byte
+		err := b.ForEach(func(k, v []byte) error {
+			var entry HashCacheEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				stale = append(stale, append([]byte{}, k...))
+				return nil
+			}
+
+			fi, err := os.Stat(string(k))
+			if err != nil || !identityMatches(entry, fi) {
+				stale = append(stale, append([]byte{}, k...))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range stale {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		removed = len(stale)
+
+		return nil
+	})
+
+	return removed, err
+}
+
+// Stats returns the number of entries currently in the cache.
+func (c *HashCache) Stats() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	count := 0
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(hashCacheBucket).ForEach(func(k, v []byte) error {
+			count++
+			return nil
+		})
+	})
+
+	return count, err
+}
+
+// Clear removes every entry from the cache.
+func (c *HashCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(hashCacheBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(hashCacheBucket)
+		return err
+	})
+}
+
+func identityMatches(entry HashCacheEntry, fi os.FileInfo) bool {
+	size, modTimeNs, inode := fileIdentity(fi)
+	return entry.Size == size && entry.ModTimeNs == modTimeNs && entry.Inode == inode
+}
+
+func fileIdentity(fi os.FileInfo) (size int64, modTimeNs int64, inode uint64) {
+	return fi.Size(), fi.ModTime().UnixNano(), inodeOf(fi)
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestHashCacheStoreAndLookup(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	filePath := filepath.Join(tmpDir, "file.txt")
+
+	err := os.WriteFile(filePath, []byte("hello"), 0644)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	fi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	_, ok := cache.Lookup(filePath, fi)
+	assert.Assert(t, !ok, "expected no entry before Store")
+
+	err = cache.Store(filePath, fi, "md5", "5d41402abc4b2a76b9719d911017c592")
+	assert.NilError(t, err)
+
+	entry, ok := cache.Lookup(filePath, fi)
+	assert.Assert(t, ok)
+	assert.Equal(t, entry.Digest, "5d41402abc4b2a76b9719d911017c592")
+	assert.Equal(t, entry.Algorithm, "md5")
+}
+
+func TestHashCacheSurvivesReopen(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	filePath := filepath.Join(tmpDir, "file.txt")
+
+	err := os.WriteFile(filePath, []byte("hello"), 0644)
+	assert.NilError(t, err)
+	fi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	err = cache.Store(filePath, fi, "md5", "5d41402abc4b2a76b9719d911017c592")
+	assert.NilError(t, err)
+	assert.NilError(t, cache.Close())
+
+	// Simulate a fresh process by reopening the same database file.
+	reopened, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer reopened.Close()
+
+	entry, ok := reopened.Lookup(filePath, fi)
+	assert.Assert(t, ok)
+	assert.Equal(t, entry.Digest, "5d41402abc4b2a76b9719d911017c592")
+}
+
+func TestHashCacheInvalidatesOnMtimeChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	filePath := filepath.Join(tmpDir, "file.txt")
+
+	err := os.WriteFile(filePath, []byte("hello"), 0644)
+	assert.NilError(t, err)
+	fi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	err = cache.Store(filePath, fi, "md5", "stale-digest")
+	assert.NilError(t, err)
+
+	future := fi.ModTime().Add(time.Hour)
+	err = os.Chtimes(filePath, future, future)
+	assert.NilError(t, err)
+
+	changedFi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	_, ok := cache.Lookup(filePath, changedFi)
+	assert.Assert(t, !ok, "entry should be invalidated after mtime changes")
+}
+
+func TestHashCacheInvalidatesOnSizeChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	filePath := filepath.Join(tmpDir, "file.txt")
+
+	err := os.WriteFile(filePath, []byte("hello"), 0644)
+	assert.NilError(t, err)
+	fi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	err = cache.Store(filePath, fi, "md5", "stale-digest")
+	assert.NilError(t, err)
+
+	err = os.WriteFile(filePath, []byte("hello, world, now longer"), 0644)
+	assert.NilError(t, err)
+
+	changedFi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	_, ok := cache.Lookup(filePath, changedFi)
+	assert.Assert(t, !ok, "entry should be invalidated after size changes")
+}
+
+func TestHashCachePrune(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+
+	staleFile := filepath.Join(tmpDir, "stale.txt")
+	liveFile := filepath.Join(tmpDir, "live.txt")
+
+	assert.NilError(t, os.WriteFile(staleFile, []byte("a"), 0644))
+	assert.NilError(t, os.WriteFile(liveFile, []byte("b"), 0644))
+
+	staleFi, err := os.Stat(staleFile)
+	assert.NilError(t, err)
+	liveFi, err := os.Stat(liveFile)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	assert.NilError(t, cache.Store(staleFile, staleFi, "md5", "a-digest"))
+	assert.NilError(t, cache.Store(liveFile, liveFi, "md5", "b-digest"))
+
+	// Remove the file backing one entry so Prune treats it as stale.
+	assert.NilError(t, os.Remove(staleFile))
+
+	removed, err := cache.Prune()
+	assert.NilError(t, err)
+	assert.Equal(t, removed, 1)
+
+	count, err := cache.Stats()
+	assert.NilError(t, err)
+	assert.Equal(t, count, 1)
+}
+
+func TestHashCacheClear(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "hashes.db")
+	filePath := filepath.Join(tmpDir, "file.txt")
+
+	assert.NilError(t, os.WriteFile(filePath, []byte("hello"), 0644))
+	fi, err := os.Stat(filePath)
+	assert.NilError(t, err)
+
+	cache, err := OpenHashCache(dbPath)
+	assert.NilError(t, err)
+	defer cache.Close()
+
+	assert.NilError(t, cache.Store(filePath, fi, "md5", "digest"))
+
+	count, err := cache.Stats()
+	assert.NilError(t, err)
+	assert.Equal(t, count, 1)
+
+	assert.NilError(t, cache.Clear())
+
+	count, err = cache.Stats()
+	assert.NilError(t, err)
+	assert.Equal(t, count, 0)
+}
@@ -0,0 +1,11 @@
+//go:build windows
+
+package storage
+
+import "os"
+
+// inodeOf always returns 0 on Windows, which has no inode concept; the
+// (size, mtime) pair is relied on there instead.
+func inodeOf(fi os.FileInfo) uint64 {
+	return 0
+}
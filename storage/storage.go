@@ -0,0 +1,24 @@
+// Package storage provides filesystem and object storage abstractions used
+// by s5cmd commands, most notably for comparing objects during sync.
+package storage
+
+import (
+	"time"
+
+	"github.com/peak/s5cmd/v2/storage/url"
+)
+
+// Object represents a local file or a remote S3 object along with the
+// metadata needed to compare it against its counterpart during a sync.
+type Object struct {
+	URL     *url.URL
+	Etag    string
+	Size    int64
+	ModTime *time.Time
+
+	// Checksums holds additional content checksums keyed by algorithm name
+	// (e.g. "sha256", "crc32c", "crc64nvme"), as reported by S3's
+	// x-amz-checksum-* headers for remote objects, or computed locally.
+	// The default MD5-based ETag is kept in Etag rather than here.
+	Checksums map[string]string
+}
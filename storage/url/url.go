@@ -0,0 +1,68 @@
+// Package url provides a thin abstraction over local filesystem paths and
+// s3:// object URLs so the rest of s5cmd can treat both uniformly.
+package url
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const s3Scheme = "s3://"
+
+// URL represents either a remote (s3://bucket/key) or a local filesystem
+// path.
+type URL struct {
+	// Path is the original, unparsed representation of the URL as given by
+	// the caller.
+	Path string
+
+	// Bucket is the S3 bucket name. Empty for local URLs.
+	Bucket string
+
+	// Key is the object key, i.e. everything after the bucket. Empty for
+	// local URLs.
+	Key string
+
+	remote bool
+}
+
+// New parses rawURL into a URL, recognizing the "s3://" scheme. Anything
+// else is treated as a local filesystem path.
+func New(rawURL string) (*URL, error) {
+	if !strings.HasPrefix(rawURL, s3Scheme) {
+		return &URL{Path: rawURL}, nil
+	}
+
+	rest := strings.TrimPrefix(rawURL, s3Scheme)
+	bucket, key, _ := strings.Cut(rest, "/")
+
+	return &URL{
+		Path:   rawURL,
+		Bucket: bucket,
+		Key:    key,
+		remote: true,
+	}, nil
+}
+
+// IsRemote reports whether the URL refers to an S3 object rather than a
+// local file.
+func (u *URL) IsRemote() bool {
+	return u.remote
+}
+
+// Absolute returns the absolute filesystem path for local URLs, or the
+// original s3:// URL string for remote ones. If a local path can't be made
+// absolute, it's returned as given.
+func (u *URL) Absolute() string {
+	if !u.remote {
+		if abs, err := filepath.Abs(u.Path); err == nil {
+			return abs
+		}
+	}
+	return u.Path
+}
+
+// String implements fmt.Stringer.
+func (u *URL) String() string {
+	return u.Path
+}